@@ -0,0 +1,45 @@
+package core
+
+import "io"
+
+// newGainReadCloser wraps rc, multiplying every 16-bit little-endian PCM
+// sample read through it by gain, while still closing the same underlying
+// rc on Close. It's the ReplayGain application path for raw (untranscoded)
+// output, where there's no ffmpeg stage to hand a `-af volume=` filter to.
+// A gain of 1.0 returns rc unwrapped.
+func newGainReadCloser(rc io.ReadCloser, gain float64) io.ReadCloser {
+	if gain == 1.0 {
+		return rc
+	}
+	return &gainReadCloser{gainReader: gainReader{r: rc, gain: gain}, closer: rc}
+}
+
+type gainReadCloser struct {
+	gainReader
+	closer io.Closer
+}
+
+func (g *gainReadCloser) Close() error { return g.closer.Close() }
+
+type gainReader struct {
+	r    io.Reader
+	gain float64
+}
+
+func (g *gainReader) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	for i := 0; i+1 < n; i += 2 {
+		sample := int16(uint16(p[i]) | uint16(p[i+1])<<8)
+		scaled := float64(sample) * g.gain
+		switch {
+		case scaled > 32767:
+			scaled = 32767
+		case scaled < -32768:
+			scaled = -32768
+		}
+		s := int16(scaled)
+		p[i] = byte(s)
+		p[i+1] = byte(s >> 8)
+	}
+	return n, err
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/navidrome/navidrome/model"
+	"github.com/navidrome/navidrome/model/request"
+)
+
+// MediaStreamer opens a Stream for a media file: resolving its transcoding
+// profile (if any), applying ReplayGain, and handing back a ready-to-read
+// Stream. Router.Stream and Router.Download call this as api.streamer.
+type MediaStreamer interface {
+	NewStream(ctx context.Context, id, format string, maxBitRate, timeOffset int) (*Stream, error)
+}
+
+type mediaStreamer struct {
+	ds         model.DataStore
+	transcoder Transcoder
+	cache      *TranscodingCache
+}
+
+// NewMediaStreamer creates the MediaStreamer used by the Subsonic Stream
+// and Download endpoints.
+func NewMediaStreamer(ds model.DataStore, transcoder Transcoder) MediaStreamer {
+	return &mediaStreamer{ds: ds, transcoder: transcoder, cache: NewTranscodingCache()}
+}
+
+func (ms *mediaStreamer) NewStream(ctx context.Context, id, format string, maxBitRate, timeOffset int) (*Stream, error) {
+	mf, err := ms.ds.MediaFile(ctx).Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("loading media file %s: %w", id, err)
+	}
+
+	rgOpts, hasReplayGain := request.ReplayGainFrom(ctx)
+	hasReplayGain = hasReplayGain && rgOpts.Mode != request.ReplayGainOff
+
+	if format == "raw" {
+		f, err := os.Open(mf.Path)
+		if err != nil {
+			return nil, fmt.Errorf("opening raw media file %s: %w", mf.Path, err)
+		}
+		stream := newRawStream(*mf, f)
+		if hasReplayGain {
+			rg := ComputeReplayGain(ctx, *mf, rgOpts)
+			if rg.Applied && !isLinearPCMContainer(mf.Path) {
+				// Raw output has no transcoder stage to hand a volume
+				// filter to, and rewriting sample pairs in place is only
+				// safe when the raw bytes actually are little-endian
+				// linear PCM (WAV) - any compressed container (MP3, FLAC,
+				// OGG, AAC, ...) would just get corrupted, so gain goes
+				// unapplied there and the X-Replaygain-Applied header
+				// must say so.
+				rg.Applied = false
+			}
+			stream.replayGain = rg
+			if rg.Applied {
+				stream.reader = newGainReadCloser(f, rg.Gain)
+			}
+		}
+		return stream, nil
+	}
+
+	profile, err := ms.ds.TranscodingProfile(ctx).FindByFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("resolving transcoding profile for format %q: %w", format, err)
+	}
+
+	command := profile.Command
+	var rg ReplayGainResult
+	if hasReplayGain {
+		rg = ComputeReplayGain(ctx, *mf, rgOpts)
+		if rg.Applied {
+			if filtered, ok := appendVolumeFilter(command, rg.Gain); ok {
+				command = filtered
+			} else {
+				// The first pipeline stage can't take a volume filter (see
+				// appendVolumeFilter), so gain goes unapplied here too.
+				rg.Applied = false
+			}
+		}
+	}
+
+	// timeOffset == 0 is overwhelmingly the common case: a client relying
+	// on HTTP Range to scrub rather than asking the transcoder itself to
+	// seek. That's also the case the transcoding cache is for, so Range
+	// requests for the same encode that are open at the same time share one
+	// transcode instead of each re-transcoding from scratch. A request made
+	// after every earlier one has closed starts a new transcode.
+	var r io.ReadCloser
+	if timeOffset == 0 {
+		key := fmt.Sprintf("%s|%s|%d", id, command, maxBitRate)
+		r, err = ms.cache.Get(ctx, key, func(ctx context.Context) (io.ReadCloser, error) {
+			return ms.transcoder.Transcode(ctx, command, mf.Path, maxBitRate, 0)
+		})
+	} else {
+		r, err = ms.transcoder.Transcode(ctx, command, mf.Path, maxBitRate, timeOffset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stream := newTranscodedStream(*mf, r, format, maxBitRate)
+	stream.replayGain = rg
+	return stream, nil
+}
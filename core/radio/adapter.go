@@ -0,0 +1,39 @@
+package radio
+
+import (
+	"context"
+	"io"
+
+	"github.com/navidrome/navidrome/core"
+)
+
+// transcoderAdapter adapts a core.Transcoder - which encodes a named input
+// file, or a stdin stream, via an admin-configured command template - to
+// the narrower Transcoder interface the mixer needs: decoding a track to
+// the canonical PCM format, and re-encoding the mixer's continuous PCM
+// output to the mount's target format.
+type transcoderAdapter struct {
+	inner     core.Transcoder
+	decodeCmd string // profile command decoding any input file to raw PCM
+	encodeCmd string // profile command encoding raw PCM to the mount's target format
+}
+
+// NewTranscoderAdapter wraps a core.Transcoder for use as a radio.Stream's
+// Transcoder, using decodeCommand to turn each track into canonical PCM
+// and encodeCommand to turn the mixed PCM into the mount's output format.
+func NewTranscoderAdapter(inner core.Transcoder, decodeCommand, encodeCommand string) Transcoder {
+	return &transcoderAdapter{inner: inner, decodeCmd: decodeCommand, encodeCmd: encodeCommand}
+}
+
+// TranscodeFile decodes the track at path to the canonical raw PCM format
+// used by the mixer, via the configured decode profile.
+func (a *transcoderAdapter) TranscodeFile(ctx context.Context, path, format string, bitRate int) (io.ReadCloser, error) {
+	return a.inner.Transcode(ctx, a.decodeCmd, path, bitRate, 0)
+}
+
+// Transcode encodes the mixer's continuous raw PCM output (read from in)
+// to format, via the configured encode profile, streaming through the
+// pipeline rather than buffering it to a temp file first.
+func (a *transcoderAdapter) Transcode(ctx context.Context, name string, in io.Reader, format string, bitRate int) (io.ReadCloser, error) {
+	return a.inner.TranscodeStream(ctx, a.encodeCmd, in, bitRate)
+}
@@ -0,0 +1,91 @@
+package radio
+
+import (
+	"context"
+	"sync"
+
+	"github.com/navidrome/navidrome/log"
+)
+
+// Manager owns the set of live mounts, starting a Stream's mixer goroutine
+// lazily on the first listener and tearing it down once the last one
+// leaves, so an unlistened mount doesn't run forever in the background.
+type Manager struct {
+	mu      sync.Mutex
+	streams map[string]*Stream
+	newFn   func(id string) (*Stream, error)
+}
+
+// NewManager creates a Manager. newFn builds the Stream for a given mount
+// id (looking up its Source and encoding parameters), and is called at
+// most once per id between periods of no listeners.
+func NewManager(newFn func(id string) (*Stream, error)) *Manager {
+	return &Manager{
+		streams: make(map[string]*Stream),
+		newFn:   newFn,
+	}
+}
+
+// Join attaches a listener to the mount identified by id, starting its
+// mixer goroutine if it isn't already running, and returns the Stream plus
+// a channel of encoded audio for this listener.
+func (m *Manager) Join(ctx context.Context, id, listenerID string) (*Stream, <-chan []byte, func(), error) {
+	m.mu.Lock()
+	s, ok := m.streams[id]
+	if !ok {
+		var err error
+		s, err = m.newFn(id)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, nil, nil, err
+		}
+		m.streams[id] = s
+		// Run on a Manager-owned context, not the joining listener's
+		// request-scoped ctx: the mount is shared by every listener and must
+		// outlive whichever HTTP request happened to start it, or the first
+		// listener disconnecting would tear down the stream for everyone
+		// else still tuned in.
+		go func() {
+			if err := s.Run(context.Background()); err != nil {
+				log.Error(context.Background(), "Radio mount stopped", "id", id, err)
+			}
+			m.retire(id, s)
+		}()
+	}
+	// AddListener must happen before we release mu: otherwise a concurrent
+	// retireIfEmpty for this id (triggered by another listener leaving)
+	// could see a zero listener count and stop/delete this stream in the
+	// window between creating/looking it up and attaching this listener.
+	ch, leave := s.AddListener(listenerID)
+	m.mu.Unlock()
+
+	return s, ch, func() {
+		leave()
+		m.retireIfEmpty(id)
+	}, nil
+}
+
+func (m *Manager) retireIfEmpty(id string) {
+	m.mu.Lock()
+	s, ok := m.streams[id]
+	if !ok || s.ListenerCount() > 0 {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.streams, id)
+	m.mu.Unlock()
+	s.Stop()
+}
+
+// retire removes id from the live set and stops s, so that when a mount's
+// mixer goroutine exits on its own (e.g. its Source is exhausted), every
+// listener still attached gets its channel closed instead of being left
+// blocked forever waiting for audio that will never arrive.
+func (m *Manager) retire(id string, s *Stream) {
+	m.mu.Lock()
+	if m.streams[id] == s {
+		delete(m.streams, id)
+	}
+	m.mu.Unlock()
+	s.Stop()
+}
@@ -0,0 +1,76 @@
+package radio
+
+// Canonical PCM format used internally by the mixer: signed 16-bit
+// little-endian, stereo, 44.1kHz. Every track is decoded to this format
+// before mixing so that crossfades and silence trimming can be done with
+// plain sample arithmetic, regardless of the source file's own format.
+const (
+	pcmSampleRate     = 44100
+	pcmChannels       = 2
+	pcmBytesPerSample = 2
+	pcmFrameSize      = pcmChannels * pcmBytesPerSample
+)
+
+// durationToFrames converts a duration in seconds to a number of PCM
+// frames (one frame = one sample per channel) at the canonical sample rate.
+func durationToFrames(seconds float64) int {
+	return int(seconds * pcmSampleRate)
+}
+
+// crossfade linearly fades `out` down to silence while fading `in` up from
+// silence, over the shorter of the two buffers, and sums the result. Both
+// buffers must hold interleaved int16 PCM frames in the canonical format.
+// The returned buffer has the same length as the shorter input.
+func crossfade(out, in []int16) []int16 {
+	n := len(out)
+	if len(in) < n {
+		n = len(in)
+	}
+	mixed := make([]int16, n)
+	frames := n / pcmChannels
+	for f := 0; f < frames; f++ {
+		fadeOut := 1 - float64(f)/float64(frames)
+		fadeIn := float64(f) / float64(frames)
+		for c := 0; c < pcmChannels; c++ {
+			i := f*pcmChannels + c
+			mixed[i] = clampInt16(float64(out[i])*fadeOut + float64(in[i])*fadeIn)
+		}
+	}
+	return mixed
+}
+
+func clampInt16(v float64) int16 {
+	switch {
+	case v > 32767:
+		return 32767
+	case v < -32768:
+		return -32768
+	default:
+		return int16(v)
+	}
+}
+
+// trimSilence drops leading and trailing frames whose absolute amplitude,
+// on every channel, stays below threshold. It is used to tighten up the
+// crossfade window so mount transitions don't include dead air.
+func trimSilence(pcm []int16, threshold int16) []int16 {
+	isSilent := func(i int) bool {
+		for c := 0; c < pcmChannels; c++ {
+			s := pcm[i+c]
+			if s > threshold || s < -threshold {
+				return false
+			}
+		}
+		return true
+	}
+
+	start := 0
+	for start+pcmChannels <= len(pcm) && isSilent(start) {
+		start += pcmChannels
+	}
+	end := len(pcm)
+	for end-pcmChannels >= start && isSilent(end-pcmChannels) {
+		end -= pcmChannels
+	}
+	return pcm[start:end]
+}
@@ -0,0 +1,88 @@
+package radio
+
+import "testing"
+
+func TestDurationToFrames(t *testing.T) {
+	if got, want := durationToFrames(1), pcmSampleRate; got != want {
+		t.Fatalf("durationToFrames(1) = %d, want %d", got, want)
+	}
+	if got, want := durationToFrames(0.5), pcmSampleRate/2; got != want {
+		t.Fatalf("durationToFrames(0.5) = %d, want %d", got, want)
+	}
+}
+
+func TestCrossfade(t *testing.T) {
+	t.Run("fades out to silence and in from silence, frame by frame", func(t *testing.T) {
+		out := []int16{1000, 1000, 1000, 1000}
+		in := []int16{0, 0, 0, 0}
+
+		mixed := crossfade(out, in)
+
+		if len(mixed) != len(out) {
+			t.Fatalf("len(mixed) = %d, want %d", len(mixed), len(out))
+		}
+		// First frame is still almost entirely `out`.
+		if mixed[0] != 1000 {
+			t.Fatalf("mixed[0] = %d, want 1000 (fadeOut=1 on first frame)", mixed[0])
+		}
+	})
+
+	t.Run("result length is the shorter of the two inputs", func(t *testing.T) {
+		out := make([]int16, 8) // 4 frames
+		in := make([]int16, 4)  // 2 frames
+
+		mixed := crossfade(out, in)
+		if len(mixed) != len(in) {
+			t.Fatalf("len(mixed) = %d, want %d", len(mixed), len(in))
+		}
+	})
+
+	t.Run("clamps overflowing sums instead of wrapping", func(t *testing.T) {
+		out := []int16{32767, 32767}
+		in := []int16{32767, 32767}
+
+		mixed := crossfade(out, in)
+		if mixed[0] != 32767 {
+			t.Fatalf("mixed[0] = %d, want 32767 (full scale, not wrapped)", mixed[0])
+		}
+		if got := clampInt16(float64(out[0]) + float64(in[0])); got != 32767 {
+			t.Fatalf("clampInt16(65534) = %d, want 32767", got)
+		}
+	})
+}
+
+func TestTrimSilence(t *testing.T) {
+	frame := func(l, r int16) []int16 { return []int16{l, r} }
+	concat := func(frames ...[]int16) []int16 {
+		var out []int16
+		for _, f := range frames {
+			out = append(out, f...)
+		}
+		return out
+	}
+
+	t.Run("drops leading and trailing near-silent frames", func(t *testing.T) {
+		pcm := concat(frame(0, 0), frame(1, -1), frame(1000, 1000), frame(2, -2), frame(0, 0))
+		got := trimSilence(pcm, 5)
+		want := frame(1000, 1000)
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Fatalf("trimSilence(...) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("all-silent input trims to empty", func(t *testing.T) {
+		pcm := concat(frame(0, 0), frame(1, -1), frame(0, 0))
+		got := trimSilence(pcm, 5)
+		if len(got) != 0 {
+			t.Fatalf("trimSilence(...) = %v, want empty", got)
+		}
+	})
+
+	t.Run("no silence to trim returns the input unchanged", func(t *testing.T) {
+		pcm := concat(frame(1000, 1000), frame(2000, -2000))
+		got := trimSilence(pcm, 5)
+		if len(got) != len(pcm) {
+			t.Fatalf("trimSilence(...) = %v, want %v", got, pcm)
+		}
+	})
+}
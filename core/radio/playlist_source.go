@@ -0,0 +1,43 @@
+package radio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/navidrome/navidrome/model"
+)
+
+// PlaylistSource is a Source that cycles through a playlist's tracks,
+// looping back to the start once it runs out - the simplest way to turn an
+// existing playlist or smart playlist into a radio mount.
+type PlaylistSource struct {
+	ds         model.DataStore
+	playlistID string
+	name       string
+	genre      string
+
+	next int
+}
+
+// NewPlaylistSource creates a Source over the playlist identified by
+// playlistID. name and genre are used for the mount's ICY headers.
+func NewPlaylistSource(ds model.DataStore, playlistID, name, genre string) *PlaylistSource {
+	return &PlaylistSource{ds: ds, playlistID: playlistID, name: name, genre: genre}
+}
+
+func (s *PlaylistSource) Name() string  { return s.name }
+func (s *PlaylistSource) Genre() string { return s.genre }
+
+func (s *PlaylistSource) Next(ctx context.Context) (model.MediaFile, error) {
+	tracks, err := s.ds.Playlist(ctx).Tracks(s.playlistID)
+	if err != nil {
+		return model.MediaFile{}, fmt.Errorf("loading radio playlist %s: %w", s.playlistID, err)
+	}
+	if len(tracks) == 0 {
+		return model.MediaFile{}, fmt.Errorf("radio playlist %s has no tracks", s.playlistID)
+	}
+
+	track := tracks[s.next%len(tracks)]
+	s.next++
+	return track, nil
+}
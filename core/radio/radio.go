@@ -0,0 +1,298 @@
+// Package radio implements continuous, Icecast-style streaming "mounts":
+// a never-ending audio stream assembled from a playlist, smart playlist or
+// internet radio definition, shared by every listener currently tuned in.
+package radio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/navidrome/navidrome/log"
+	"github.com/navidrome/navidrome/model"
+)
+
+// Transcoder is the subset of core.Transcoder the mixer needs: encoding a
+// raw PCM stream (the mixer's output) into the format requested by
+// listeners. Declared locally, rather than depending on core.Transcoder's
+// full surface, per the usual Go "accept narrow interfaces" convention.
+type Transcoder interface {
+	Transcode(ctx context.Context, name string, in io.Reader, format string, bitRate int) (io.ReadCloser, error)
+	TranscodeFile(ctx context.Context, path, format string, bitRate int) (io.ReadCloser, error)
+}
+
+// NowPlaying describes the track currently being broadcast on a Stream.
+type NowPlaying struct {
+	MediaFile model.MediaFile
+	StartedAt time.Time
+}
+
+// Title renders the ICY `StreamTitle` payload for the current track.
+func (n NowPlaying) Title() string {
+	if n.MediaFile.Artist == "" {
+		return n.MediaFile.Title
+	}
+	return fmt.Sprintf("%s - %s", n.MediaFile.Artist, n.MediaFile.Title)
+}
+
+// Source supplies the sequence of tracks a Stream mixes and broadcasts. A
+// playlist, a smart playlist or an "internet radio" station definition are
+// all just different Sources.
+type Source interface {
+	// Name identifies the source for ICY `icy-name`/`icy-genre` headers.
+	Name() string
+	Genre() string
+	// Next returns the next track to play. It may block (e.g. a smart
+	// playlist re-evaluating its criteria) and must be safe to call
+	// repeatedly for as long as the mount is live.
+	Next(ctx context.Context) (model.MediaFile, error)
+}
+
+// listener is one HTTP client currently tuned in to a Stream.
+type listener struct {
+	id string
+	ch chan []byte
+}
+
+// Stream is a single continuous mount: one mixer/encoder pass, fanned out
+// to every listener currently attached, so N clients never cost N decodes.
+type Stream struct {
+	id         string
+	source     Source
+	transcoder Transcoder
+	format     string
+	bitRate    int
+
+	mu         sync.RWMutex
+	listeners  map[string]*listener
+	nowPlaying NowPlaying
+
+	// OnNowPlaying, if set, is called on track changes so callers can
+	// publish the update to Subsonic's getNowPlaying (and scrobbling).
+	OnNowPlaying func(NowPlaying)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewStream creates a Stream for the given source, encoding the mix to
+// format at bitRate (kbps) via the shared transcoder pipeline. The mixer
+// goroutine is not started until Run is called.
+func NewStream(id string, source Source, transcoder Transcoder, format string, bitRate int) *Stream {
+	return &Stream{
+		id:         id,
+		source:     source,
+		transcoder: transcoder,
+		format:     format,
+		bitRate:    bitRate,
+		listeners:  make(map[string]*listener),
+	}
+}
+
+// Run decodes and mixes tracks from the source, encodes the result to the
+// configured format, and broadcasts it to all attached listeners. It runs
+// until ctx is cancelled or the source returns an error.
+func (s *Stream) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	defer close(s.done)
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := s.mix(ctx, pw)
+		_ = pw.CloseWithError(err)
+	}()
+
+	encoded, err := s.transcoder.Transcode(ctx, "radio-"+s.id, pr, s.format, s.bitRate)
+	if err != nil {
+		return fmt.Errorf("starting radio encoder: %w", err)
+	}
+	defer encoded.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := encoded.Read(buf)
+		if n > 0 {
+			s.broadcast(buf[:n])
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// mix pulls tracks from the source, decodes each to the canonical PCM
+// format, crossfades across track boundaries, and writes the continuous
+// PCM stream to w.
+func (s *Stream) mix(ctx context.Context, w io.Writer) error {
+	const crossfadeSeconds = 3.0
+	var prevTail []int16
+
+	for {
+		track, err := s.source.Next(ctx)
+		if err != nil {
+			return fmt.Errorf("radio source %s exhausted: %w", s.source.Name(), err)
+		}
+
+		pcm, err := s.decodeToPCM(ctx, track)
+		if err != nil {
+			log.Error(ctx, "Skipping track that failed to decode for radio", "id", s.id, "track", track.ID, err)
+			continue
+		}
+		pcm = trimSilence(pcm, 256)
+
+		current := NowPlaying{MediaFile: track, StartedAt: time.Now()}
+		s.mu.Lock()
+		s.nowPlaying = current
+		s.mu.Unlock()
+		if s.OnNowPlaying != nil {
+			s.OnNowPlaying(current)
+		}
+
+		fadeFrames := durationToFrames(crossfadeSeconds) * pcmChannels
+		if prevTail != nil {
+			n := fadeFrames
+			if len(pcm) < n {
+				n = len(pcm)
+			}
+			mixed := crossfade(prevTail, pcm[:n])
+			if _, err := w.Write(int16SliceToBytes(mixed)); err != nil {
+				return err
+			}
+			// If this track is shorter than the crossfade window, prevTail
+			// only got partially consumed above; the rest of it still needs
+			// to be heard rather than silently dropped.
+			if len(prevTail) > n {
+				if _, err := w.Write(int16SliceToBytes(prevTail[n:])); err != nil {
+					return err
+				}
+			}
+			pcm = pcm[n:]
+			prevTail = nil
+		}
+
+		if len(pcm) <= fadeFrames {
+			if _, err := w.Write(int16SliceToBytes(pcm)); err != nil {
+				return err
+			}
+			prevTail = nil
+			continue
+		}
+
+		body, tail := pcm[:len(pcm)-fadeFrames], pcm[len(pcm)-fadeFrames:]
+		if _, err := w.Write(int16SliceToBytes(body)); err != nil {
+			return err
+		}
+		prevTail = tail
+	}
+}
+
+// decodeToPCM runs track through the transcoder pipeline into the
+// canonical raw PCM format used by the mixer.
+func (s *Stream) decodeToPCM(ctx context.Context, track model.MediaFile) ([]int16, error) {
+	r, err := s.transcoder.TranscodeFile(ctx, track.Path, "pcm", 0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return bytesToInt16Slice(raw), nil
+}
+
+// AddListener registers a new client and returns a channel of encoded audio
+// chunks for it to forward to its HTTP response, plus a function to
+// unregister it.
+func (s *Stream) AddListener(id string) (<-chan []byte, func()) {
+	l := &listener{id: id, ch: make(chan []byte, 64)}
+
+	s.mu.Lock()
+	s.listeners[id] = l
+	s.mu.Unlock()
+
+	return l.ch, func() { s.RemoveListener(id) }
+}
+
+// RemoveListener detaches a client from the mount.
+func (s *Stream) RemoveListener(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.listeners[id]; ok {
+		close(l.ch)
+		delete(s.listeners, id)
+	}
+}
+
+// ListenerCount reports how many clients are currently tuned in.
+func (s *Stream) ListenerCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.listeners)
+}
+
+// NowPlaying reports the track currently being broadcast.
+func (s *Stream) NowPlaying() NowPlaying {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nowPlaying
+}
+
+// Name and Genre mirror the underlying Source, for ICY headers.
+func (s *Stream) Name() string  { return s.source.Name() }
+func (s *Stream) Genre() string { return s.source.Genre() }
+
+// Stop shuts down the mixer goroutine and disconnects all listeners.
+func (s *Stream) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, l := range s.listeners {
+		close(l.ch)
+		delete(s.listeners, id)
+	}
+}
+
+func (s *Stream) broadcast(chunk []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for id, l := range s.listeners {
+		select {
+		case l.ch <- chunk:
+		default:
+			// A slow listener that can't keep up loses this chunk rather
+			// than stalling the shared mixer for everyone else.
+			log.Trace(context.Background(), "Dropping radio chunk for slow listener", "stream", s.id, "listener", id)
+		}
+	}
+}
+
+func bytesToInt16Slice(b []byte) []int16 {
+	out := make([]int16, len(b)/2)
+	for i := range out {
+		out[i] = int16(b[2*i]) | int16(b[2*i+1])<<8
+	}
+	return out
+}
+
+func int16SliceToBytes(s []int16) []byte {
+	out := make([]byte, len(s)*2)
+	for i, v := range s {
+		out[2*i] = byte(v)
+		out[2*i+1] = byte(v >> 8)
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/navidrome/navidrome/conf"
+	"github.com/navidrome/navidrome/log"
+	"github.com/navidrome/navidrome/model"
+	"github.com/navidrome/navidrome/model/request"
+)
+
+// ReplayGainResult is the outcome of applying ReplayGainOptions to a track:
+// the linear gain factor to multiply samples by, and whether any gain
+// beyond unity was actually applied (for the X-Replaygain-Applied header).
+type ReplayGainResult struct {
+	Gain    float64
+	Applied bool
+}
+
+// ComputeReplayGain resolves the linear gain factor for mf under opts. When
+// the track (and, for album mode, the album) has no RG tags, it falls back
+// to the configured default gain via analyzeReplayGain for this stream
+// only - that fallback is not a real measurement, so it is never written
+// back to the MediaFile as if it were tag data (see analyzeReplayGain).
+func ComputeReplayGain(ctx context.Context, mf model.MediaFile, opts request.ReplayGainOptions) ReplayGainResult {
+	if opts.Mode == request.ReplayGainOff || opts.Mode == "" {
+		return ReplayGainResult{Gain: 1.0, Applied: false}
+	}
+
+	gainDB, peak, ok := replayGainTags(mf, opts.Mode)
+	if !ok {
+		gainDB, peak = analyzeReplayGain(ctx, mf.Path)
+	}
+
+	gainDB += opts.PreampDB
+	gain := math.Pow(10, gainDB/20)
+
+	if opts.PreventClipping && peak > 0 {
+		if max := 1.0 / peak; gain > max {
+			gain = max
+		}
+	}
+
+	return ReplayGainResult{Gain: gain, Applied: gain != 1.0}
+}
+
+// replayGainTags reads the track's (or, in album mode, the album's)
+// existing RG tags off the MediaFile. ok is false when the relevant tag is
+// absent, signalling the caller to fall back to on-demand analysis.
+func replayGainTags(mf model.MediaFile, mode request.ReplayGainMode) (gainDB, peak float64, ok bool) {
+	switch mode {
+	case request.ReplayGainAlbum:
+		if mf.RGAlbumGain == 0 && mf.RGAlbumPeak == 0 {
+			return 0, 0, false
+		}
+		return mf.RGAlbumGain, mf.RGAlbumPeak, true
+	default:
+		if mf.RGTrackGain == 0 && mf.RGTrackPeak == 0 {
+			return 0, 0, false
+		}
+		return mf.RGTrackGain, mf.RGTrackPeak, true
+	}
+}
+
+// analyzeReplayGain would run an EBU R128/ReplayGain 2.0 analysis pass over
+// the file at path; that's not implemented yet, so it just returns the
+// configured default gain, applied for this stream only. It is
+// deliberately never persisted as if it were a real measurement - doing so
+// would permanently mis-tag an unanalyzed track as analyzed, and risk a
+// lost update against any concurrent write to the same MediaFile row.
+func analyzeReplayGain(ctx context.Context, path string) (gainDB, peak float64) {
+	log.Trace(ctx, "No ReplayGain tags found, using default gain", "path", path, "defaultGain", conf.Server.ReplayGain.DefaultGain)
+	return conf.Server.ReplayGain.DefaultGain, 1.0
+}
+
+// volumeFilterArg renders the ffmpeg `-af volume=` argument for a linear
+// gain factor, for transcoder profiles that apply ReplayGain via ffmpeg
+// rather than a PCM multiply step.
+func volumeFilterArg(gain float64) string {
+	return "volume=" + strconv.FormatFloat(gain, 'f', -1, 64)
+}
+
+// appendVolumeFilter inserts an `-af volume=` argument into command's first
+// pipeline stage (the one that reads the input file), right before that
+// stage's output argument (its last token), so the computed ReplayGain is
+// actually applied by the transcoder, rather than just reported in the
+// X-Replaygain-Applied header. `-af` is an ffmpeg flag, so this only
+// applies when that first stage actually invokes ffmpeg - e.g. a
+// `sox %s ... | opusenc ...` profile's first stage is sox, which doesn't
+// understand `-af` at all, so the command is returned unmodified and ok is
+// false. The same happens when a first-stage token contains a single quote,
+// since the stage is rebuilt with every token single-quoted. Callers must
+// not treat gain as applied when ok is false.
+func appendVolumeFilter(command string, gain float64) (out string, ok bool) {
+	stages := splitPipeline(command)
+	argv, err := shlexTokenize(stages[0])
+	if err != nil || len(argv) < 2 || commandBaseName(argv[0]) != "ffmpeg" {
+		return command, false
+	}
+
+	// ffmpeg ignores options trailing its last output, so the filter goes
+	// before argv's last token rather than after it.
+	last := len(argv) - 1
+	args := append(append(append([]string{}, argv[:last]...), "-af", volumeFilterArg(gain)), argv[last])
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if strings.Contains(a, "'") {
+			return command, false
+		}
+		quoted[i] = "'" + a + "'"
+	}
+	first := strings.Join(quoted, " ")
+	if len(stages) == 1 {
+		return first, true
+	}
+	return first + " | " + strings.Join(stages[1:], "|"), true
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/navidrome/navidrome/model"
+)
+
+// Stream is an open, readable rendition of a MediaFile - either the raw
+// file itself or a transcoder's output - as handed back by
+// MediaStreamer.NewStream to the Subsonic Stream/Download endpoints.
+type Stream struct {
+	mf                     model.MediaFile
+	reader                 io.ReadCloser
+	seeker                 io.Seeker // non-nil only for raw, seekable streams
+	format                 string
+	estimatedContentLength int
+	replayGain             ReplayGainResult
+}
+
+// newRawStream wraps an already-open raw media file, which is always
+// seekable.
+func newRawStream(mf model.MediaFile, f *os.File) *Stream {
+	return &Stream{mf: mf, reader: f, seeker: f, format: filepath.Ext(mf.Path)}
+}
+
+// isLinearPCMContainer reports whether path's container holds uncompressed,
+// directly sample-addressable little-endian PCM - i.e. it's safe to rewrite
+// sample pairs in place (as ReplayGain's raw-output path does) without
+// corrupting a compressed bitstream. AIFF is not included: its samples are
+// big-endian, which the raw-output path does not handle.
+func isLinearPCMContainer(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav", ".wave":
+		return true
+	default:
+		return false
+	}
+}
+
+// newTranscodedStream wraps a transcoder's output pipe, which can only be
+// read forward. estimatedContentLength is a best-effort size (derived from
+// the track's duration and the requested bitrate), used to support Range
+// requests on an otherwise non-seekable stream; it is 0 when unknown.
+func newTranscodedStream(mf model.MediaFile, r io.ReadCloser, format string, maxBitRate int) *Stream {
+	return &Stream{
+		mf:                     mf,
+		reader:                 r,
+		format:                 format,
+		estimatedContentLength: estimateContentLength(mf, maxBitRate),
+	}
+}
+
+// estimateContentLength guesses the encoded size, in bytes, of mf
+// transcoded at maxBitRate (kbps), for clients that ask for it and for
+// Range support on non-seekable streams. It returns 0 when there isn't
+// enough information to even guess.
+func estimateContentLength(mf model.MediaFile, maxBitRate int) int {
+	if maxBitRate <= 0 || mf.Duration <= 0 {
+		return 0
+	}
+	return int(mf.Duration) * maxBitRate * 1000 / 8
+}
+
+func (s *Stream) Read(p []byte) (int, error) { return s.reader.Read(p) }
+func (s *Stream) Close() error               { return s.reader.Close() }
+
+// Seekable reports whether the underlying reader supports Seek - true for
+// raw files, false for a transcoder's output pipe.
+func (s *Stream) Seekable() bool { return s.seeker != nil }
+
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	if s.seeker == nil {
+		return 0, errors.New("stream is not seekable")
+	}
+	return s.seeker.Seek(offset, whence)
+}
+
+func (s *Stream) Name() string                { return s.mf.Path }
+func (s *Stream) ModTime() time.Time          { return s.mf.UpdatedAt }
+func (s *Stream) Duration() float32           { return s.mf.Duration }
+func (s *Stream) MediaFile() model.MediaFile  { return s.mf }
+func (s *Stream) EstimatedContentLength() int { return s.estimatedContentLength }
+
+// ReplayGain reports the ReplayGain NewStream resolved for this stream, so
+// callers that already have a Stream (e.g. to set X-Replaygain-Applied)
+// don't need to call ComputeReplayGain a second time. It's the zero
+// ReplayGainResult if ReplayGain wasn't requested.
+func (s *Stream) ReplayGain() ReplayGainResult { return s.replayGain }
+
+func (s *Stream) ContentType() string {
+	if ct := mime.TypeByExtension("." + s.format); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
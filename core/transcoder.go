@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/navidrome/navidrome/log"
+)
+
+// Transcoder runs an admin-configured transcoding profile command line and
+// returns its encoded output as a stream. timeOffset (seconds) is honored
+// by the template itself when it uses the `%f` seek placeholder; otherwise
+// callers must discard the leading timeOffset seconds of output themselves.
+type Transcoder interface {
+	// Transcode runs command against the file at path.
+	Transcode(ctx context.Context, command, path string, maxBitRate, timeOffset int) (f io.ReadCloser, err error)
+	// TranscodeStream runs command against in, fed to the pipeline's first
+	// stage on stdin instead of a `%s` path placeholder - e.g. for
+	// re-encoding the core/radio mixer's continuous PCM output.
+	TranscodeStream(ctx context.Context, command string, in io.Reader, maxBitRate int) (f io.ReadCloser, err error)
+}
+
+// ValidateTranscodingCommand pre-parses a transcoding profile's command
+// line, so admins get a clear error when saving a profile instead of a
+// broken one discovered on the next stream.
+func ValidateTranscodingCommand(command string) error {
+	_, err := parseTranscodingTemplate(command)
+	return err
+}
+
+type transcoder struct{}
+
+// NewTranscoder creates a Transcoder that execs profile command lines
+// directly (never through a shell), parsed by ValidateTranscodingCommand's
+// tokenizer.
+func NewTranscoder() Transcoder {
+	return transcoder{}
+}
+
+func (transcoder) Transcode(ctx context.Context, command, path string, maxBitRate, timeOffset int) (io.ReadCloser, error) {
+	tpl, err := parseTranscodingTemplate(command)
+	if err != nil {
+		return nil, err
+	}
+	return runPipeline(ctx, command, tpl, nil, path, maxBitRate, timeOffset)
+}
+
+func (transcoder) TranscodeStream(ctx context.Context, command string, in io.Reader, maxBitRate int) (io.ReadCloser, error) {
+	tpl, err := parseTranscodingTemplate(command)
+	if err != nil {
+		return nil, err
+	}
+	// There's no input file, so `%s` has nothing meaningful to resolve to;
+	// by convention it becomes "-", the usual stdin placeholder for tools
+	// like ffmpeg (`-i -`).
+	return runPipeline(ctx, command, tpl, in, "-", maxBitRate, 0)
+}
+
+// runPipeline execs tpl's stages, wiring each one's stdout to the next
+// one's stdin - the same as a shell pipeline, but without ever invoking a
+// shell. If in is non-nil, it feeds the first stage's stdin instead of the
+// stage reading path itself (e.g. ffmpeg's `-i %s`).
+func runPipeline(ctx context.Context, command string, tpl *transcodingTemplate, in io.Reader, path string, maxBitRate, timeOffset int) (io.ReadCloser, error) {
+	cmds := make([]*exec.Cmd, len(tpl.stages))
+	for i, stage := range tpl.stages {
+		argv := substitute(stage, path, maxBitRate, timeOffset)
+		cmds[i] = exec.CommandContext(ctx, argv[0], argv[1:]...)
+	}
+
+	if in != nil {
+		cmds[0].Stdin = in
+	}
+
+	for i := 0; i < len(cmds)-1; i++ {
+		pipe, err := cmds[i].StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("wiring transcoder pipeline: %w", err)
+		}
+		cmds[i+1].Stdin = pipe
+	}
+
+	out, err := cmds[len(cmds)-1].StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening transcoder output: %w", err)
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("starting transcoder %q: %w", cmd.Path, err)
+		}
+	}
+
+	log.Trace(ctx, "Started transcoding pipeline", "command", command, "path", path,
+		"maxBitRate", maxBitRate, "timeOffset", timeOffset, "seeksInPlace", tpl.usesSeekPlaceholder())
+
+	return &transcoderOutput{stdout: out, cmds: cmds}, nil
+}
+
+// transcoderOutput adapts a (possibly multi-stage) pipeline's final stdout
+// to io.ReadCloser, making sure every stage is waited on so none are left
+// as zombies once the stream is closed.
+type transcoderOutput struct {
+	stdout io.ReadCloser
+	cmds   []*exec.Cmd
+}
+
+func (p *transcoderOutput) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *transcoderOutput) Close() error {
+	err := p.stdout.Close()
+	for _, cmd := range p.cmds {
+		_ = cmd.Wait()
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// transcodingTemplate is a parsed, ready-to-exec transcoder command line,
+// split into one or more pipeline stages (stage boundaries are literal `|`
+// tokens in the template, outside of quotes). Navidrome never hands a
+// template to a shell: each stage's argv is tokenized and
+// placeholder-substituted directly, so correct quoting is all an admin
+// needs to get right - there's no shell escaping to worry about, and no
+// shell interpolation vulnerability to introduce.
+type transcodingTemplate struct {
+	stages [][]string // each stage is an un-substituted argv
+}
+
+// disallowedCommands lists argv[0] values that would just re-introduce a
+// shell, and its injection surface, one level down.
+var disallowedCommands = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "ksh": true,
+	"cmd": true, "powershell": true,
+}
+
+// commandBaseName reduces argv[0] to the bare name disallowedCommands is
+// keyed on, so an absolute or relative path (`/bin/sh`, `./bin/bash`, or a
+// quoted `'C:\Windows\cmd.exe'`) can't bypass the check, the way an exact
+// match on argv[0] itself would. Both `/` and `\` count as separators on
+// every platform.
+func commandBaseName(arg0 string) string {
+	base := arg0[strings.LastIndexAny(arg0, `/\`)+1:]
+	return strings.TrimSuffix(strings.ToLower(base), ".exe")
+}
+
+// parseTranscodingTemplate tokenizes a transcoding profile's command line
+// into one or more pipeline stages, validating that it can be exec'd
+// directly. It's called both when a profile is saved, so errors surface
+// immediately, and before each transcode.
+func parseTranscodingTemplate(command string) (*transcodingTemplate, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("empty transcoding command")
+	}
+
+	var stages [][]string
+	for _, stage := range splitPipeline(command) {
+		argv, err := shlexTokenize(stage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transcoding command %q: %w", stage, err)
+		}
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("empty pipeline stage in %q", command)
+		}
+		if disallowedCommands[commandBaseName(argv[0])] {
+			return nil, fmt.Errorf("transcoding command must not spawn a shell, got %q", argv[0])
+		}
+		stages = append(stages, argv)
+	}
+	return &transcodingTemplate{stages: stages}, nil
+}
+
+// splitPipeline splits command on literal, unquoted `|` tokens, the only
+// pipeline syntax a template supports.
+func splitPipeline(command string) []string {
+	var stages []string
+	var cur strings.Builder
+	inSingle, inDouble := false, false
+
+	for _, r := range command {
+		switch {
+		case r == '\'' && !inDouble:
+			inSingle = !inSingle
+			cur.WriteRune(r)
+		case r == '"' && !inSingle:
+			inDouble = !inDouble
+			cur.WriteRune(r)
+		case r == '|' && !inSingle && !inDouble:
+			stages = append(stages, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	stages = append(stages, cur.String())
+	return stages
+}
+
+// shlexTokenize splits a single pipeline stage into argv, honoring single
+// and double quotes and backslash escapes the way a POSIX shell would
+// tokenize a command line, but without any of a shell's expansion,
+// redirection or substitution behavior.
+func shlexTokenize(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	hasToken := false
+	inSingle, inDouble, escaped := false, false, false
+
+	flush := func() {
+		if hasToken {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			hasToken = false
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			hasToken = true
+			escaped = false
+		case r == '\\' && !inSingle:
+			escaped = true
+		case r == '\'' && !inDouble:
+			inSingle = !inSingle
+			hasToken = true
+		case r == '"' && !inSingle:
+			inDouble = !inDouble
+			hasToken = true
+		case (r == ' ' || r == '\t') && !inSingle && !inDouble:
+			flush()
+		default:
+			cur.WriteRune(r)
+			hasToken = true
+		}
+	}
+	flush()
+
+	if inSingle || inDouble {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing escape character in %q", s)
+	}
+	return tokens, nil
+}
+
+// substitute fills in the %s (input path), %b (bitrate in kbps) and %f
+// (seek offset in seconds) placeholders in argv, wherever they appear in a
+// token (so `%bk` becomes e.g. `192k`). Each token is scanned once and
+// substituted values are never rescanned, rather than going through
+// %-style string formatting, so a path or other value containing a literal
+// '%' can't be misinterpreted.
+func substitute(argv []string, path string, maxBitRate, timeOffset int) []string {
+	r := strings.NewReplacer("%s", path, "%b", strconv.Itoa(maxBitRate), "%f", strconv.Itoa(timeOffset))
+	out := make([]string, len(argv))
+	for i, tok := range argv {
+		out[i] = r.Replace(tok)
+	}
+	return out
+}
+
+// usesSeekPlaceholder reports whether the template's first stage (the one
+// that reads the input file) has a %f token, meaning the transcoder itself
+// can start decoding at timeOffset instead of Navidrome transcoding from
+// the start and discarding up to that point.
+func (t *transcodingTemplate) usesSeekPlaceholder() bool {
+	if len(t.stages) == 0 {
+		return false
+	}
+	for _, tok := range t.stages[0] {
+		if tok == "%f" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package core
+
+import "testing"
+
+func TestShlexTokenize(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"simple", "ffmpeg -i %s -f mp3 -", []string{"ffmpeg", "-i", "%s", "-f", "mp3", "-"}, false},
+		{"double quoted with space", `ffmpeg -i "my file.flac" -f mp3 -`, []string{"ffmpeg", "-i", "my file.flac", "-f", "mp3", "-"}, false},
+		{"single quoted", `echo 'a b'`, []string{"echo", "a b"}, false},
+		{"escaped space", `echo a\ b`, []string{"echo", "a b"}, false},
+		{"adjacent quoted segments join into one token", `echo a"b"'c'`, []string{"echo", "abc"}, false},
+		{"empty string returns no tokens", "", nil, false},
+		{"unterminated double quote", `echo "a`, nil, true},
+		{"unterminated single quote", `echo 'a`, nil, true},
+		{"trailing escape", `echo a\`, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := shlexTokenize(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("shlexTokenize(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if !stringSlicesEqual(got, tc.want) {
+				t.Fatalf("shlexTokenize(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitPipeline(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"no pipe", "ffmpeg -i %s -f mp3 -", []string{"ffmpeg -i %s -f mp3 -"}},
+		{"one pipe", "sox %s -t wav - | opusenc --bitrate %b - -", []string{"sox %s -t wav - ", " opusenc --bitrate %b - -"}},
+		{"pipe inside quotes is not a stage boundary", `echo "a|b"`, []string{`echo "a|b"`}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := splitPipeline(tc.in)
+			if !stringSlicesEqual(got, tc.want) {
+				t.Fatalf("splitPipeline(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTranscodingTemplate(t *testing.T) {
+	t.Run("valid multi-stage pipeline", func(t *testing.T) {
+		tpl, err := parseTranscodingTemplate("sox %s -t wav - | opusenc --bitrate %b - -")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(tpl.stages) != 2 {
+			t.Fatalf("got %d stages, want 2", len(tpl.stages))
+		}
+	})
+
+	t.Run("empty command is rejected", func(t *testing.T) {
+		if _, err := parseTranscodingTemplate("   "); err == nil {
+			t.Fatal("expected an error for an empty command")
+		}
+	})
+
+	disallowed := []string{"sh", "bash", "/bin/sh", "/usr/bin/bash", "C:\\Windows\\cmd.exe", "CMD"}
+	for _, cmd := range disallowed {
+		t.Run("rejects shell spawn via "+cmd, func(t *testing.T) {
+			if _, err := parseTranscodingTemplate(cmd + " -c echo hi"); err == nil {
+				t.Fatalf("expected %q to be rejected as a shell-spawning command", cmd)
+			}
+		})
+	}
+
+	t.Run("allows a non-shell command with a path-like argv[0]", func(t *testing.T) {
+		if _, err := parseTranscodingTemplate("/usr/bin/ffmpeg -i %s -f mp3 -"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSubstitute(t *testing.T) {
+	argv := []string{"ffmpeg", "-i", "%s", "-b:a", "%bk", "-ss", "%f", "-f", "mp3", "-"}
+	got := substitute(argv, "/music/track.flac", 192, 30)
+	want := []string{"ffmpeg", "-i", "/music/track.flac", "-b:a", "192k", "-ss", "30", "-f", "mp3", "-"}
+	if !stringSlicesEqual(got, want) {
+		t.Fatalf("substitute() = %v, want %v", got, want)
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
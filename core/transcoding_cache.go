@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// TranscodingCache memoizes in-flight transcodes by key, so that concurrent
+// readers of the same encode - such as overlapping HTTP Range requests from
+// a client scrubbing a long track - share one transcoder invocation and its
+// growing output instead of each re-running it from scratch, and discarding
+// everything before the requested offset. An entry lives only while it has
+// an open reader; it is not kept for later requests.
+type TranscodingCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// NewTranscodingCache creates an empty TranscodingCache.
+func NewTranscodingCache() *TranscodingCache {
+	return &TranscodingCache{entries: make(map[string]*cacheEntry)}
+}
+
+// Get returns a reader over the transcode identified by key. The first
+// caller for a given key starts newSource in the background, writing its
+// output to a shared temp file as it arrives; every later caller for that
+// same key reads from that same file instead of invoking newSource again,
+// for as long as at least one reader of it is still open. The entry is
+// discarded, and its temp file removed, as soon as its last reader calls
+// Close, whether or not the transcode has finished: requests that don't
+// overlap in time (such as Range requests issued one after another) each
+// start a fresh transcode.
+func (c *TranscodingCache) Get(ctx context.Context, key string, newSource func(ctx context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		f, err := os.CreateTemp("", "navidrome-transcode-*")
+		if err != nil {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("creating transcoding cache file: %w", err)
+		}
+		e = &cacheEntry{file: f}
+		e.cond = sync.NewCond(&e.mu)
+		c.entries[key] = e
+		// Not the first caller's request-scoped ctx: the transcode is shared,
+		// and that request ending must not cut it short for other readers.
+		go e.fill(context.Background(), newSource)
+	}
+	e.mu.Lock()
+	e.refs++
+	e.mu.Unlock()
+	c.mu.Unlock()
+
+	return &cacheReader{cache: c, key: key, entry: e}, nil
+}
+
+// cacheEntry backs one in-flight transcode with a temp file that one
+// goroutine (fill) appends to, while any number of cacheReaders tail it
+// from their own independent read position.
+type cacheEntry struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	file *os.File
+	size int64
+	done bool
+	err  error
+	refs int
+}
+
+func (e *cacheEntry) fill(ctx context.Context, newSource func(ctx context.Context) (io.ReadCloser, error)) {
+	src, err := newSource(ctx)
+	if err != nil {
+		e.finish(err)
+		return
+	}
+	defer src.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := e.file.WriteAt(buf[:n], e.size); werr != nil {
+				e.finish(werr)
+				return
+			}
+			e.mu.Lock()
+			e.size += int64(n)
+			e.cond.Broadcast()
+			e.mu.Unlock()
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			e.finish(err)
+			return
+		}
+	}
+}
+
+func (e *cacheEntry) finish(err error) {
+	e.mu.Lock()
+	e.err, e.done = err, true
+	e.cond.Broadcast()
+	e.mu.Unlock()
+}
+
+// cacheReader reads an entry's backing file from the start, blocking until
+// fill has written enough bytes rather than seeing a premature EOF.
+type cacheReader struct {
+	cache *TranscodingCache
+	key   string
+	entry *cacheEntry
+	pos   int64
+}
+
+func (r *cacheReader) Read(p []byte) (int, error) {
+	e := r.entry
+	e.mu.Lock()
+	for r.pos >= e.size && !e.done {
+		e.cond.Wait()
+	}
+	available := e.size - r.pos
+	done, err := e.done, e.err
+	e.mu.Unlock()
+
+	if available <= 0 {
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			return 0, io.EOF
+		}
+	}
+
+	if int64(len(p)) > available {
+		p = p[:available]
+	}
+	n, err := e.file.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF {
+		// A short read at the current end of the written-so-far region
+		// isn't end-of-stream unless fill is actually done; Read is called
+		// again once more has been written or done is set.
+		err = nil
+	}
+	return n, err
+}
+
+func (r *cacheReader) Close() error {
+	e := r.entry
+	e.mu.Lock()
+	e.refs--
+	refs := e.refs
+	e.mu.Unlock()
+	if refs > 0 {
+		return nil
+	}
+
+	r.cache.mu.Lock()
+	if r.cache.entries[r.key] == e {
+		delete(r.cache.entries, r.key)
+	}
+	r.cache.mu.Unlock()
+
+	name := e.file.Name()
+	closeErr := e.file.Close()
+	_ = os.Remove(name)
+	return closeErr
+}
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/navidrome/navidrome/model"
+)
+
+// SaveTranscodingProfile validates a transcoding profile's command line
+// before persisting it, so an admin gets a clear error at save time
+// instead of a broken profile discovered on the next stream.
+func SaveTranscodingProfile(ctx context.Context, ds model.DataStore, profile model.TranscodingProfile) error {
+	if err := ValidateTranscodingCommand(profile.Command); err != nil {
+		return fmt.Errorf("invalid transcoding profile %q: %w", profile.Name, err)
+	}
+	return ds.TranscodingProfile(ctx).Put(&profile)
+}
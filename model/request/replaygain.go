@@ -0,0 +1,38 @@
+package request
+
+import "context"
+
+// ReplayGainMode selects which of a track's gain tags (if any) to apply.
+type ReplayGainMode string
+
+const (
+	ReplayGainTrack ReplayGainMode = "track"
+	ReplayGainAlbum ReplayGainMode = "album"
+	ReplayGainOff   ReplayGainMode = "off"
+)
+
+// ReplayGainOptions controls how the streaming pipeline computes and
+// applies gain. Mode and PreampDB normally come from the `rgMode`/
+// `rgPreamp` query parameters or the requesting user's preferences;
+// PreventClipping from `rgPreventClipping`.
+type ReplayGainOptions struct {
+	Mode            ReplayGainMode
+	PreampDB        float64
+	PreventClipping bool
+}
+
+type replayGainKey struct{}
+
+// WithReplayGain attaches the ReplayGain options resolved for a stream
+// request to ctx, so the transcoder can pick them up without threading
+// them through every call in between.
+func WithReplayGain(ctx context.Context, opts ReplayGainOptions) context.Context {
+	return context.WithValue(ctx, replayGainKey{}, opts)
+}
+
+// ReplayGainFrom retrieves the ReplayGain options previously attached with
+// WithReplayGain, if any.
+func ReplayGainFrom(ctx context.Context) (ReplayGainOptions, bool) {
+	opts, ok := ctx.Value(replayGainKey{}).(ReplayGainOptions)
+	return opts, ok
+}
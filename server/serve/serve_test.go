@@ -0,0 +1,59 @@
+package serve
+
+import "testing"
+
+func TestSanitizeASCIIFilename(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain ascii is untouched", "Track 01.mp3", "Track 01.mp3"},
+		{"quote and backslash are replaced", `a"b\c`, "a_b_c"},
+		{"non-ascii runes are replaced", "Björk.mp3", "Bj_rk.mp3"},
+		{"control characters are replaced", "a\tb\nc", "a_b_c"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeASCIIFilename(tc.in); got != tc.want {
+				t.Fatalf("sanitizeASCIIFilename(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRFC5987(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain ascii is untouched", "track01", "track01"},
+		{"space is percent-encoded", "a b", "a%20b"},
+		{"non-ascii bytes are percent-encoded", "Björk", "Bj%C3%B6rk"},
+		{"attr-chars are left as-is", "a-b.c_d", "a-b.c_d"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := encodeRFC5987(tc.in); got != tc.want {
+				t.Fatalf("encodeRFC5987(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	t.Run("no filename returns the bare disposition", func(t *testing.T) {
+		if got := contentDisposition(DispositionAttachment, ""); got != "attachment" {
+			t.Fatalf("contentDisposition(...) = %q, want %q", got, "attachment")
+		}
+	})
+
+	t.Run("non-ascii filename sets both filename and filename*", func(t *testing.T) {
+		got := contentDisposition(DispositionAttachment, "Björk.mp3")
+		want := `attachment; filename="Bj_rk.mp3"; filename*=UTF-8''Bj%C3%B6rk.mp3`
+		if got != want {
+			t.Fatalf("contentDisposition(...) = %q, want %q", got, want)
+		}
+	})
+}
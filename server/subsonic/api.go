@@ -0,0 +1,98 @@
+package subsonic
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/navidrome/navidrome/core"
+	"github.com/navidrome/navidrome/core/radio"
+	"github.com/navidrome/navidrome/log"
+	"github.com/navidrome/navidrome/model"
+	"github.com/navidrome/navidrome/server/subsonic/responses"
+)
+
+// Archiver is the subset of core's archiving service the Subsonic API needs
+// to zip up albums, artists and playlists for Download.
+type Archiver interface {
+	ZipAlbum(ctx context.Context, id, format string, maxBitRate int, w io.Writer) error
+	ZipArtist(ctx context.Context, id, format string, maxBitRate int, w io.Writer) error
+	ZipPlaylist(ctx context.Context, id, format string, maxBitRate int, w io.Writer) error
+}
+
+// Scrobbler is the subset of core's scrobbling service radio mounts use to
+// publish now-playing updates, the same way a regular client-driven Stream
+// does, so `getNowPlaying` and scrobbling plugins see radio playback too.
+type Scrobbler interface {
+	NowPlaying(ctx context.Context, trackID string) error
+}
+
+// Router backs the Subsonic REST handlers (Stream, Download, Radio, ...)
+// with their concrete services.
+type Router struct {
+	ds        model.DataStore
+	streamer  core.MediaStreamer
+	archiver  Archiver
+	scrobbler Scrobbler
+	radio     *radio.Manager
+}
+
+// NewRouter wires up a Router with a MediaStreamer backed by the
+// shell-quoted transcoder profiles (core.NewTranscoder), so Stream and
+// Download actually run transcodes through the shlex-parsed pipeline, and
+// a radio.Manager sharing that same Transcoder for its mounts.
+func NewRouter(ds model.DataStore, archiver Archiver, scrobbler Scrobbler) *Router {
+	transcoder := core.NewTranscoder()
+	return &Router{
+		ds:        ds,
+		streamer:  core.NewMediaStreamer(ds, transcoder),
+		archiver:  archiver,
+		scrobbler: scrobbler,
+		radio:     radio.NewManager(newRadioStream(ds, transcoder, scrobbler)),
+	}
+}
+
+// newRadioStream builds the per-mount constructor radio.NewManager calls
+// the first time a listener joins mount id: id is treated as a playlist ID,
+// decoded to PCM and re-encoded to MP3 through the same shlex-parsed
+// transcoder profiles used for regular streaming.
+func newRadioStream(ds model.DataStore, transcoder core.Transcoder, scrobbler Scrobbler) func(id string) (*radio.Stream, error) {
+	const (
+		decodeProfile = `ffmpeg -i %s -f s16le -ar 44100 -ac 2 -`
+		encodeProfile = `ffmpeg -f s16le -ar 44100 -ac 2 -i %s -b:a %bk -f mp3 -`
+	)
+	return func(id string) (*radio.Stream, error) {
+		pl, err := ds.Playlist(context.Background()).Get(id)
+		if err != nil {
+			return nil, err
+		}
+		source := radio.NewPlaylistSource(ds, id, pl.Name, "Radio")
+		adapter := radio.NewTranscoderAdapter(transcoder, decodeProfile, encodeProfile)
+		stream := radio.NewStream(id, source, adapter, "mp3", 128)
+		stream.OnNowPlaying = func(np radio.NowPlaying) {
+			if err := scrobbler.NowPlaying(context.Background(), np.MediaFile.ID); err != nil {
+				log.Error(context.Background(), "Could not publish radio now-playing", "id", id, "track", np.MediaFile.ID, err)
+			}
+		}
+		return stream, nil
+	}
+}
+
+// Routes mounts the handlers in this package onto mux, alongside whatever
+// other Subsonic endpoints the full server registers.
+func (api *Router) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("/rest/stream", api.handle(api.Stream))
+	mux.HandleFunc("/rest/download", api.handle(api.Download))
+	mux.HandleFunc("/rest/radio", api.handle(api.Radio))
+}
+
+// handle adapts a Subsonic endpoint - which may write its response body
+// directly (as Stream, Download and Radio do) and return a nil response -
+// to a plain http.HandlerFunc.
+func (api *Router) handle(h func(w http.ResponseWriter, r *http.Request) (*responses.Subsonic, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := h(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
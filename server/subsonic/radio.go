@@ -0,0 +1,132 @@
+package subsonic
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/navidrome/navidrome/core/radio"
+	"github.com/navidrome/navidrome/log"
+	"github.com/navidrome/navidrome/server/subsonic/responses"
+	"github.com/navidrome/navidrome/utils"
+)
+
+// icyMetaInt is the byte interval at which we inject ICY metadata blocks,
+// matching the value most Icecast-aware clients (VLC, foobar2000) default to.
+const icyMetaInt = 16 * 1024
+
+// Radio serves a continuous, never-ending audio mount identified by id -
+// a playlist, smart playlist or internet radio station - with optional
+// ICY metadata for clients that request it. Unlike Stream, the response
+// never ends on its own; it runs until the client disconnects.
+func (api *Router) Radio(w http.ResponseWriter, r *http.Request) (*responses.Subsonic, error) {
+	ctx := r.Context()
+	id, err := requiredParamString(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	listenerID := utils.ParamString(r, "c") + "-" + r.RemoteAddr
+	stream, chunks, leave, err := api.radio.Join(ctx, id, listenerID)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	icy := r.Header.Get("icy-metadata") == "1"
+
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("icy-name", stream.Name())
+	w.Header().Set("icy-genre", stream.Genre())
+	w.Header().Set("icy-br", fmt.Sprintf("%d", api.radioBitRate(id)))
+	if icy {
+		w.Header().Set("icy-metaint", fmt.Sprintf("%d", icyMetaInt))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	out := io.Writer(w)
+	if icy {
+		out = newIcyWriter(w, stream)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return nil, nil
+			}
+			if _, err := out.Write(chunk); err != nil {
+				log.Trace(ctx, "Radio listener disconnected", "id", id, err)
+				return nil, nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// radioBitRate is a placeholder until per-mount encoding parameters are
+// surfaced by the radio manager; it reports the configured default.
+func (api *Router) radioBitRate(id string) int {
+	return 128
+}
+
+// icyWriter wraps an http.ResponseWriter and injects an ICY metadata block
+// (a length byte followed by up to 255*16 bytes of zero-padded
+// `StreamTitle='...';`) every icyMetaInt bytes of audio, as required by the
+// SHOUTcast/Icecast metadata protocol.
+type icyWriter struct {
+	w      http.ResponseWriter
+	stream *radio.Stream
+	sent   int
+}
+
+func newIcyWriter(w http.ResponseWriter, stream *radio.Stream) *icyWriter {
+	return &icyWriter{w: w, stream: stream}
+}
+
+func (i *icyWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		remaining := icyMetaInt - i.sent
+		n := len(p)
+		if n > remaining {
+			n = remaining
+		}
+		written, err := i.w.Write(p[:n])
+		total += written
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+		i.sent += written
+
+		if i.sent == icyMetaInt {
+			if err := i.writeMetadata(); err != nil {
+				return total, err
+			}
+			i.sent = 0
+		}
+	}
+	return total, nil
+}
+
+func (i *icyWriter) writeMetadata() error {
+	title := fmt.Sprintf("StreamTitle='%s';", i.stream.NowPlaying().Title())
+	// Metadata block length is declared in multiples of 16 bytes, in a
+	// single leading byte, with the payload zero-padded to that length.
+	blocks := (len(title) + 15) / 16
+	if blocks > 255 {
+		blocks = 255
+		title = title[:255*16]
+	}
+	padded := make([]byte, blocks*16+1)
+	padded[0] = byte(blocks)
+	copy(padded[1:], title)
+	_, err := i.w.Write(padded)
+	return err
+}
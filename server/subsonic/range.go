@@ -0,0 +1,86 @@
+package subsonic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// byteRange represents a single `bytes=start-end` range, as parsed from
+// a Range request header. end is inclusive, following the HTTP spec.
+type byteRange struct {
+	start, end int64
+}
+
+// parseByteRange parses a `Range: bytes=start-end` header into a single
+// byteRange, resolving an open-ended end (`bytes=500-`) or a suffix range
+// (`bytes=-500`) against total, the known or estimated size of the full
+// content. Only single ranges are supported; multi-range requests
+// (`bytes=0-10,20-30`) are rejected with an error, as callers fall back to
+// sending the full content in that case.
+func parseByteRange(header string, total int64) (byteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return byteRange{}, fmt.Errorf("invalid range header %q", header)
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return byteRange{}, fmt.Errorf("multi-range requests are not supported: %q", header)
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return byteRange{}, fmt.Errorf("invalid range header %q", header)
+	}
+
+	startStr, endStr := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	switch {
+	case startStr == "" && endStr == "":
+		return byteRange{}, fmt.Errorf("invalid range header %q", header)
+	case startStr == "":
+		// Suffix range: the last N bytes of the content.
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil {
+			return byteRange{}, err
+		}
+		start := total - n
+		if start < 0 {
+			start = 0
+		}
+		return byteRange{start: start, end: total - 1}, nil
+	default:
+		start, err := strconv.ParseInt(startStr, 10, 64)
+		if err != nil {
+			return byteRange{}, err
+		}
+		end := total - 1
+		if endStr != "" {
+			end, err = strconv.ParseInt(endStr, 10, 64)
+			if err != nil {
+				return byteRange{}, err
+			}
+		}
+		if end > total-1 {
+			end = total - 1
+		}
+		if start > end {
+			return byteRange{}, fmt.Errorf("invalid range %q for content of length %d", header, total)
+		}
+		return byteRange{start: start, end: end}, nil
+	}
+}
+
+// CopyRange copies the `length` bytes starting at `start` from r to w. It is
+// meant for streams that cannot seek (e.g. a transcoder's stdout pipe) but
+// whose total size is known or estimated, such as a transcoding cache still
+// being filled in. The bytes before start are read and discarded, as there
+// is no way to skip ahead on a plain io.Reader.
+func CopyRange(w io.Writer, r io.Reader, start, length int64) (int64, error) {
+	if start > 0 {
+		if _, err := io.CopyN(io.Discard, r, start); err != nil {
+			return 0, err
+		}
+	}
+	return io.CopyN(w, r, length)
+}
@@ -0,0 +1,81 @@
+package subsonic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		name    string
+		header  string
+		total   int64
+		want    byteRange
+		wantErr bool
+	}{
+		{"start and end", "bytes=0-499", 1000, byteRange{0, 499}, false},
+		{"open ended", "bytes=500-", 1000, byteRange{500, 999}, false},
+		{"suffix range", "bytes=-500", 1000, byteRange{500, 999}, false},
+		{"suffix range larger than total clamps to 0", "bytes=-5000", 1000, byteRange{0, 999}, false},
+		{"end beyond total clamps to total-1", "bytes=900-2000", 1000, byteRange{900, 999}, false},
+		{"missing bytes prefix", "0-499", 1000, byteRange{}, true},
+		{"multi-range is rejected", "bytes=0-10,20-30", 1000, byteRange{}, true},
+		{"start and end both empty", "bytes=-", 1000, byteRange{}, true},
+		{"start greater than end", "bytes=500-100", 1000, byteRange{}, true},
+		{"non-numeric start", "bytes=abc-100", 1000, byteRange{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseByteRange(tc.header, tc.total)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseByteRange(%q, %d) error = %v, wantErr %v", tc.header, tc.total, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if got != tc.want {
+				t.Fatalf("parseByteRange(%q, %d) = %+v, want %+v", tc.header, tc.total, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	t.Run("copies the requested window, discarding bytes before start", func(t *testing.T) {
+		src := strings.NewReader("0123456789")
+		var dst bytes.Buffer
+
+		n, err := CopyRange(&dst, src, 3, 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n != 4 {
+			t.Fatalf("copied %d bytes, want 4", n)
+		}
+		if dst.String() != "3456" {
+			t.Fatalf("copied %q, want %q", dst.String(), "3456")
+		}
+	})
+
+	t.Run("start of zero skips no bytes", func(t *testing.T) {
+		src := strings.NewReader("abcdef")
+		var dst bytes.Buffer
+
+		if _, err := CopyRange(&dst, src, 0, 3); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dst.String() != "abc" {
+			t.Fatalf("copied %q, want %q", dst.String(), "abc")
+		}
+	})
+
+	t.Run("start beyond the available data errors", func(t *testing.T) {
+		src := strings.NewReader("abc")
+		var dst bytes.Buffer
+
+		if _, err := CopyRange(&dst, src, 10, 1); err == nil {
+			t.Fatal("expected an error when start is beyond the available data")
+		}
+	})
+}
@@ -6,79 +6,113 @@ import (
 	"io"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/navidrome/navidrome/conf"
 	"github.com/navidrome/navidrome/core"
 	"github.com/navidrome/navidrome/log"
 	"github.com/navidrome/navidrome/model"
 	"github.com/navidrome/navidrome/model/request"
+	"github.com/navidrome/navidrome/server/serve"
 	"github.com/navidrome/navidrome/server/subsonic/responses"
 	"github.com/navidrome/navidrome/utils"
 )
 
+// streamCacheDuration bounds how long intermediate caches (CDNs, browser
+// disk cache) may hold on to a streamed or downloaded file. Content for a
+// given id is effectively immutable between re-tags or profile changes, but
+// should still stop being served stale well within a day of one.
+const streamCacheDuration = 1 * time.Hour
+
+// replayGainOptionsFrom builds request.ReplayGainOptions from the
+// `rgMode`, `rgPreamp` and `rgPreventClipping` query parameters, defaulting
+// to no gain applied so existing clients that don't know about ReplayGain
+// see no change in behavior.
+func replayGainOptionsFrom(r *http.Request) request.ReplayGainOptions {
+	mode := request.ReplayGainMode(utils.ParamString(r, "rgMode"))
+	if mode == "" {
+		mode = request.ReplayGainOff
+	}
+	return request.ReplayGainOptions{
+		Mode:            mode,
+		PreampDB:        utils.ParamFloat(r, "rgPreamp", 0),
+		PreventClipping: utils.ParamBool(r, "rgPreventClipping", false),
+	}
+}
+
 func (api *Router) serveStream(ctx context.Context, w http.ResponseWriter, r *http.Request, stream *core.Stream, id string) {
 	if stream.Seekable() {
+		serve.SetServeHeaders(w, serve.ServeHeaderOptions{CacheDuration: streamCacheDuration})
 		http.ServeContent(w, r, stream.Name(), stream.ModTime(), stream)
+		return
+	}
+
+	serve.SetServeHeaders(w, serve.ServeHeaderOptions{
+		ContentType:   stream.ContentType(),
+		CacheDuration: streamCacheDuration,
+		LastModified:  stream.ModTime(),
+	})
+
+	total := stream.EstimatedContentLength()
+	// Only advertise Range support when we actually have a size estimate to
+	// serve one against; otherwise a client would just keep retrying Range
+	// requests the server can never honor.
+	if total > 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
 	} else {
-		var reqRange = r.Header.Get("Range")
-		// "safari or ios range 0-1 informal support, just wait transcode complete and return content length.
-		// In next request use seekable data. need enable TranscodingCacheSize."
-		if reqRange != "" && strings.HasPrefix(reqRange, "bytes=") {
-			startPosition := 0
-			endPosition := 0
-			reqBlockRange := strings.Split(strings.Split(reqRange, "=")[1], "-")
-			startPosition, _ = strconv.Atoi(reqBlockRange[0])
-			if len(reqBlockRange) > 1 && reqBlockRange[1] != "" {
-				tmp, _ := strconv.Atoi(reqBlockRange[1])
-				endPosition = tmp
+		w.Header().Set("Accept-Ranges", "none")
+	}
+	reqRange := r.Header.Get("Range")
+
+	// A transcoded, non-seekable stream can still serve a real Range request
+	// as long as we know (or can estimate) its total size: the transcoding
+	// cache backs the stream with a growing file, so reading from the start
+	// and discarding bytes up to the requested offset is just slower than a
+	// true seek, not impossible.
+	if reqRange != "" && total > 0 {
+		br, err := parseByteRange(reqRange, int64(total))
+		if err != nil {
+			log.Trace(ctx, "Ignoring unsupported range, sending full content", "id", id, "range", reqRange, err)
+		} else {
+			length := br.end - br.start + 1
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", br.start, br.end, total))
+			w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+			w.WriteHeader(http.StatusPartialContent)
+
+			if r.Method == "HEAD" {
+				return
 			}
 
-			if startPosition == 0 && endPosition == 1 {
-				data, _ := io.ReadAll(stream)
-				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", startPosition, endPosition, len(data)))
-				w.Header().Set("Accept-Ranges", "bytes")
-				// w.Header().Set("Transfer-Encoding", "chunked")
-				w.Header().Set("Content-Type", "audio/aac")
-				w.WriteHeader(206)
-				w.Header().Set("Status", "206")
-				one := make([]byte, 2)
-				// time.Sleep(10 * time.Second)
-				// io.ReadFull(stream, one)
-				c, err := w.Write(one)
-				if log.CurrentLevel() >= log.LevelDebug {
-					if err != nil {
-						log.Error(ctx, "Error sending range 0-1", "id", id, err)
-					} else {
-						log.Trace(ctx, "Success sending range 0-1", "id", id, "size", c)
-					}
+			c, err := CopyRange(w, stream, br.start, length)
+			if log.CurrentLevel() >= log.LevelDebug {
+				if err != nil {
+					log.Error(ctx, "Error sending range", "id", id, "range", reqRange, err)
+				} else {
+					log.Trace(ctx, "Success sending range", "id", id, "range", reqRange, "size", c)
 				}
 			}
-		} else {
-			// If the stream doesn't provide a size (i.e. is not seekable), we can't support ranges/content-length
-			w.Header().Set("Accept-Ranges", "none")
-			w.Header().Set("Content-Type", stream.ContentType())
+			return
+		}
+	}
 
-			estimateContentLength := utils.ParamBool(r, "estimateContentLength", false)
+	estimateContentLength := utils.ParamBool(r, "estimateContentLength", false)
 
-			// if Client requests the estimated content-length, send it
-			if estimateContentLength {
-				length := strconv.Itoa(stream.EstimatedContentLength())
-				log.Trace(ctx, "Estimated content-length", "contentLength", length)
-				w.Header().Set("Content-Length", length)
-			}
+	// if Client requests the estimated content-length, send it
+	if estimateContentLength {
+		length := strconv.Itoa(total)
+		log.Trace(ctx, "Estimated content-length", "contentLength", length)
+		w.Header().Set("Content-Length", length)
+	}
 
-			if r.Method == "HEAD" {
-				go func() { _, _ = io.Copy(io.Discard, stream) }()
+	if r.Method == "HEAD" {
+		go func() { _, _ = io.Copy(io.Discard, stream) }()
+	} else {
+		c, err := io.Copy(w, stream)
+		if log.CurrentLevel() >= log.LevelDebug {
+			if err != nil {
+				log.Error(ctx, "Error sending transcoded file", "id", id, err)
 			} else {
-				c, err := io.Copy(w, stream)
-				if log.CurrentLevel() >= log.LevelDebug {
-					if err != nil {
-						log.Error(ctx, "Error sending transcoded file", "id", id, err)
-					} else {
-						log.Trace(ctx, "Success sending transcode file", "id", id, "size", c)
-					}
-				}
+				log.Trace(ctx, "Success sending transcode file", "id", id, "size", c)
 			}
 		}
 	}
@@ -92,8 +126,12 @@ func (api *Router) Stream(w http.ResponseWriter, r *http.Request) (*responses.Su
 	}
 	maxBitRate := utils.ParamInt(r, "maxBitRate", 0)
 	format := utils.ParamString(r, "format")
+	timeOffset := utils.ParamInt(r, "timeOffset", 0)
+
+	rgOpts := replayGainOptionsFrom(r)
+	ctx = request.WithReplayGain(ctx, rgOpts)
 
-	stream, err := api.streamer.NewStream(ctx, id, format, maxBitRate)
+	stream, err := api.streamer.NewStream(ctx, id, format, maxBitRate, timeOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +146,16 @@ func (api *Router) Stream(w http.ResponseWriter, r *http.Request) (*responses.Su
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("X-Content-Duration", strconv.FormatFloat(float64(stream.Duration()), 'G', -1, 32))
 
+	if rgOpts.Mode != request.ReplayGainOff {
+		// A gain NewStream computed but could not apply is reported as unity.
+		rg := stream.ReplayGain()
+		applied := 1.0
+		if rg.Applied {
+			applied = rg.Gain
+		}
+		w.Header().Set("X-Replaygain-Applied", strconv.FormatFloat(applied, 'f', 3, 64))
+	}
+
 	api.serveStream(ctx, w, r, stream, id)
 
 	return nil, nil
@@ -153,22 +201,26 @@ func (api *Router) Download(w http.ResponseWriter, r *http.Request) (*responses.
 	}
 
 	setHeaders := func(name string) {
-		name = strings.ReplaceAll(name, ",", "_")
-		disposition := fmt.Sprintf("attachment; filename=\"%s.zip\"", name)
-		w.Header().Set("Content-Disposition", disposition)
-		w.Header().Set("Content-Type", "application/zip")
+		serve.SetServeHeaders(w, serve.ServeHeaderOptions{
+			ContentType: "application/zip",
+			Disposition: serve.DispositionAttachment,
+			Filename:    name + ".zip",
+		})
 	}
 
 	switch v := entity.(type) {
 	case *model.MediaFile:
-		stream, err := api.streamer.NewStream(ctx, id, format, maxBitRate)
+		stream, err := api.streamer.NewStream(ctx, id, format, maxBitRate, 0)
 
 		if err != nil {
 			return nil, err
 		}
 
-		disposition := fmt.Sprintf("attachment; filename=\"%s\"", stream.Name())
-		w.Header().Set("Content-Disposition", disposition)
+		serve.SetServeHeaders(w, serve.ServeHeaderOptions{
+			Disposition:  serve.DispositionAttachment,
+			Filename:     stream.Name(),
+			LastModified: stream.ModTime(),
+		})
 
 		api.serveStream(ctx, w, r, stream, id)
 		return nil, nil